@@ -0,0 +1,46 @@
+package kotlin
+
+import "text/template"
+
+// ktConfigurationTmpl emits the DI container wiring for the generated
+// Queries interface, gated behind Kotlin.Framework. Spring and Micronaut
+// both want a single factory method producing the interface binding rather
+// than relying on component scanning to find QueriesImpl, since QueriesImpl
+// takes a DataSource constructor argument that the container already
+// manages. This wiring assumes the jdbc QueriesImpl(dataSource: DataSource)
+// constructor, so Generate rejects Kotlin.Framework combined with
+// Kotlin.Driver == "r2dbc" rather than emitting a file that references a
+// constructor the r2dbc QueriesImpl doesn't have.
+var ktConfigurationTmpl = `// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc {{.SqlcVersion}}
+
+package {{.Package}}
+
+{{if eq .Settings.Kotlin.Framework "spring" -}}
+import org.springframework.context.annotation.Bean
+import org.springframework.context.annotation.Configuration
+import javax.sql.DataSource
+
+@Configuration
+class QueriesConfiguration {
+  @Bean
+  fun queries(dataSource: DataSource): Queries = QueriesImpl(dataSource)
+}
+{{- end}}
+{{if eq .Settings.Kotlin.Framework "micronaut" -}}
+import io.micronaut.context.annotation.Factory
+import javax.inject.Singleton
+import javax.sql.DataSource
+
+@Factory
+class QueriesConfiguration {
+  @Singleton
+  fun queries(dataSource: DataSource): Queries = QueriesImpl(dataSource)
+}
+{{- end}}
+`
+
+func newConfigurationTemplate(funcMap template.FuncMap) *template.Template {
+	return template.Must(template.New("table").Funcs(funcMap).Parse(ktConfigurationTmpl))
+}
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"regexp"
 	"sort"
@@ -15,6 +14,7 @@ import (
 	"github.com/kyleconroy/sqlc/internal/inflection"
 	"github.com/kyleconroy/sqlc/internal/metadata"
 	"github.com/kyleconroy/sqlc/internal/plugin"
+	"github.com/kyleconroy/sqlc/internal/sqlparam"
 )
 
 var ktIdentPattern = regexp.MustCompile("[^a-zA-Z0-9_]+")
@@ -33,6 +33,7 @@ type Enum struct {
 
 type Field struct {
 	Name    string
+	DBName  string
 	Type    ktType
 	Comment string
 }
@@ -134,6 +135,101 @@ func (v Params) Bindings() string {
 	return indent(strings.Join(out, "\n"), 10, 0)
 }
 
+// BindingsForVar is like Bindings, but reads each bound value off a field of
+// varName instead of off a same-named function parameter. It's used by
+// :copyfrom and :batchexec, which bind one row at a time out of a data class
+// instance rather than out of the method's own arguments.
+func (v Params) BindingsForVar(varName string) string {
+	if v.isEmpty() {
+		return ""
+	}
+	var out []string
+	for i, f := range v.Struct.JDBCParamBindings {
+		out = append(out, jdbcSet(f.Type, i+1, varName+"."+f.Name))
+	}
+	return indent(strings.Join(out, "\n"), 10, 0)
+}
+
+// r2dbcBind returns the Kotlin expression used to bind a single parameter on
+// an io.r2dbc.spi.Statement. Unlike JDBC, R2DBC parameter positions are
+// zero-indexed and nullable values must go through bindNull with an explicit
+// type token rather than a single overloaded setter.
+func r2dbcBind(t ktType, idx int, name string) string {
+	pos := idx - 1
+	var bind string
+	if t.IsEnum && t.IsArray {
+		bind = fmt.Sprintf(`stmt.bind(%d, %s.map { v -> v.value }.toTypedArray())`, pos, name)
+	} else if t.IsEnum {
+		bind = fmt.Sprintf(`stmt.bind(%d, %s.value)`, pos, name)
+	} else if t.IsArray {
+		bind = fmt.Sprintf(`stmt.bind(%d, %s.toTypedArray())`, pos, name)
+	} else {
+		bind = fmt.Sprintf(`stmt.bind(%d, %s)`, pos, name)
+	}
+	if t.IsNull {
+		return fmt.Sprintf(`if (%s == null) stmt.bindNull(%d, %s) else %s`, name, pos, r2dbcNullClass(t), bind)
+	}
+	return bind
+}
+
+// r2dbcNullClass returns the class token passed to bindNull for a column of
+// type t, matching whatever type r2dbcBind's non-null branch actually binds:
+// a String for an enum (bound via its .value), an Array for an array column
+// (enum-array binds an Array<String> of values, any other array binds its
+// element's own array type), and the column's own class otherwise.
+func r2dbcNullClass(t ktType) string {
+	if t.IsEnum && t.IsArray {
+		return "Array<String>::class.java"
+	}
+	if t.IsEnum {
+		return "String::class.java"
+	}
+	if t.IsArray {
+		return fmt.Sprintf("Array<%s>::class.java", t.Name)
+	}
+	return fmt.Sprintf("%s::class.java", t.Name)
+}
+
+// r2dbcGet returns the Kotlin expression used to read a single column off an
+// io.r2dbc.spi.Row.
+func r2dbcGet(t ktType, idx int) string {
+	pos := idx - 1
+	if t.IsEnum && t.IsArray {
+		return fmt.Sprintf(`(row.get(%d, Array<String>::class.java) ?: emptyArray()).map { v -> %s.lookup(v)!! }.toList()`, pos, t.Name)
+	}
+	if t.IsEnum {
+		return fmt.Sprintf(`%s.lookup(row.get(%d, String::class.java))!!`, t.Name, pos)
+	}
+	if t.IsArray {
+		return fmt.Sprintf(`(row.get(%d, Array<%s>::class.java) ?: emptyArray()).toList()`, pos, t.Name)
+	}
+	return fmt.Sprintf(`row.get(%d, %s::class.java)`, pos, t.Name)
+}
+
+func (v Params) R2dbcBindings() string {
+	if v.isEmpty() {
+		return ""
+	}
+	var out []string
+	for i, f := range v.Struct.JDBCParamBindings {
+		out = append(out, r2dbcBind(f.Type, i+1, f.Name))
+	}
+	return indent(strings.Join(out, "\n"), 10, 0)
+}
+
+func (v QueryValue) R2dbcResultSet() string {
+	var out []string
+	if v.Struct == nil {
+		return r2dbcGet(v.Typ, 1)
+	}
+	for i, f := range v.Struct.Fields {
+		out = append(out, r2dbcGet(f.Type, i+1))
+	}
+	ret := indent(strings.Join(out, ",\n"), 4, -1)
+	ret = indent(v.Struct.Name+"(\n"+ret+"\n)", 12, 0)
+	return ret
+}
+
 func jdbcGet(t ktType, idx int) string {
 	if t.IsEnum && t.IsArray {
 		return fmt.Sprintf(`(results.getArray(%d).array as Array<String>).map { v -> %s.lookup(v)!! }.toList()`, idx, t.Name)
@@ -202,10 +298,18 @@ type Query struct {
 	ConstantName string
 	SQL          string
 	SourceName   string
+	Table        string // target table name, set for :copyfrom queries
 	Ret          QueryValue
 	Arg          Params
 }
 
+// ReadOnly reports whether a query's generated method should be marked
+// @Transactional(readOnly = true) under the Spring/Micronaut framework
+// integrations: :one and :many queries only read, everything else mutates.
+func (q Query) ReadOnly() bool {
+	return q.Cmd == ":one" || q.Cmd == ":many"
+}
+
 func ktEnumValueName(value string) string {
 	id := strings.Replace(value, "-", "_", -1)
 	id = strings.Replace(id, ":", "_", -1)
@@ -290,6 +394,7 @@ func buildDataClasses(req *plugin.CodeGenRequest) []Struct {
 			for _, column := range table.Columns {
 				s.Fields = append(s.Fields, Field{
 					Name:    memberName(column.Name, req.Settings),
+					DBName:  column.Name,
 					Type:    makeType(req, column),
 					Comment: column.Comment,
 				})
@@ -433,16 +538,25 @@ func ktColumnName(c *plugin.Column, pos int) string {
 	return fmt.Sprintf("column_%d", pos+1)
 }
 
-var postgresPlaceholderRegexp = regexp.MustCompile(`\B\$\d+\b`)
-
-// HACK: jdbc doesn't support numbered parameters, so we need to transform them to question marks...
-// But there's no access to the SQL parser here, so we just do a dumb regexp replace instead. This won't work if
-// the literal strings contain matching values, but good enough for a prototype.
-func jdbcSQL(s, engine string) string {
-	if engine == "postgresql" {
-		return postgresPlaceholderRegexp.ReplaceAllString(s, "?")
+// querySQL rewrites a query's numbered PostgreSQL parameters ($1, $2, ...)
+// into the placeholder syntax the target driver expects. R2DBC accepts the
+// engine's native placeholder syntax directly, so it's left untouched;
+// JDBC has no support for numbered parameters, so postgresql queries are
+// rewritten to "?" using the sqlparam tokenizer, which (unlike a regexp
+// replace) won't touch a "$1"-shaped sequence inside a string literal,
+// dollar-quoted body, or comment.
+func querySQL(req *plugin.CodeGenRequest, s string) (string, error) {
+	if req.Settings.Kotlin.Driver == "r2dbc" {
+		return s, nil
+	}
+	if req.Settings.Engine != "postgresql" {
+		return s, nil
 	}
-	return s
+	rewritten, _, err := sqlparam.Rewrite(s, sqlparam.QuestionMark)
+	if err != nil {
+		return "", fmt.Errorf("rewrite query placeholders: %w", err)
+	}
+	return rewritten, nil
 }
 
 func buildQueries(req *plugin.CodeGenRequest, structs []Struct) ([]Query, error) {
@@ -454,8 +568,12 @@ func buildQueries(req *plugin.CodeGenRequest, structs []Struct) ([]Query, error)
 		if query.Cmd == "" {
 			continue
 		}
-		if query.Cmd == metadata.CmdCopyFrom {
-			return nil, errors.New("Support for CopyFrom in Kotlin is not implemented")
+		if req.Settings.Kotlin.Driver == "r2dbc" && (query.Cmd == metadata.CmdCopyFrom || query.Cmd == ":batchexec") {
+			return nil, fmt.Errorf("%s: :copyfrom and :batchexec are not supported with the r2dbc driver", query.Name)
+		}
+		sql, err := querySQL(req, query.Text)
+		if err != nil {
+			return nil, err
 		}
 
 		gq := Query{
@@ -465,10 +583,14 @@ func buildQueries(req *plugin.CodeGenRequest, structs []Struct) ([]Query, error)
 			FieldName:    sdk.LowerTitle(query.Name) + "Stmt",
 			MethodName:   sdk.LowerTitle(query.Name),
 			SourceName:   query.Filename,
-			SQL:          jdbcSQL(query.Text, req.Settings.Engine),
+			SQL:          sql,
 			Comments:     query.Comments,
 		}
 
+		if query.Cmd == metadata.CmdCopyFrom && query.InsertIntoTable != nil {
+			gq.Table = query.InsertIntoTable.Name
+		}
+
 		var cols []goColumn
 		for _, p := range query.Params {
 			cols = append(cols, goColumn{
@@ -565,6 +687,12 @@ interface Queries {
   {{- if eq .Cmd ":execresult"}}
   fun {{.MethodName}}({{.Arg.Args}}): Long
   {{- end}}
+  {{- if eq .Cmd ":copyfrom"}}
+  fun {{.MethodName}}(rows: Iterable<{{.Arg.Struct.Name}}>)
+  {{- end}}
+  {{- if eq .Cmd ":batchexec"}}
+  fun {{.MethodName}}(rows: Iterable<{{.Arg.Struct.Name}}>): IntArray
+  {{- end}}
   {{end}}
 }
 `
@@ -631,15 +759,56 @@ data class {{.Ret.Type}} ( {{- range $i, $e := .Ret.Struct.Fields}}
   {{- end}}
 )
 {{end}}
+
+{{if or (eq .Cmd ":copyfrom") (eq .Cmd ":batchexec")}}
+data class {{.Arg.Struct.Name}} ( {{- range $i, $e := .Arg.Struct.Fields}}
+  {{- if $i }},{{end}}
+  val {{.Name}}: {{.Type}}
+  {{- end}}
+)
+{{end}}
+{{end}}
+
+{{if .EmitPgCopyHelper}}
+// pgCopyEscape renders a single value in PostgreSQL's text COPY format:
+// backslash, tab, newline and carriage return are backslash-escaped, and a
+// null value is written as the literal \N.
+private fun pgCopyEscape(value: Any?): String {
+  if (value == null) {
+    return "\\N"
+  }
+  val sb = StringBuilder()
+  for (c in value.toString()) {
+    when (c) {
+      '\\' -> sb.append("\\\\")
+      '\t' -> sb.append("\\t")
+      '\n' -> sb.append("\\n")
+      '\r' -> sb.append("\\r")
+      else -> sb.append(c)
+    }
+  }
+  return sb.toString()
+}
 {{end}}
 
+{{if eq .Settings.Kotlin.Framework "spring"}}
+@Repository
+class QueriesImpl(private val dataSource: DataSource) : Queries {
+{{else if eq .Settings.Kotlin.Framework "micronaut"}}
+@Singleton
+class QueriesImpl(private val dataSource: DataSource) : Queries {
+{{else}}
 class QueriesImpl(private val conn: Connection) : Queries {
+{{end}}
 {{range .Queries}}
 {{if eq .Cmd ":one"}}
 {{range .Comments}}//{{.}}
 {{end}}
   @Throws(SQLException::class)
+  {{if or (eq $.Settings.Kotlin.Framework "spring") (eq $.Settings.Kotlin.Framework "micronaut")}}@Transactional(readOnly = {{.ReadOnly}})
+  {{end -}}
   override fun {{.MethodName}}({{.Arg.Args}}): {{.Ret.Type}}? {
+    {{if and (ne $.Settings.Kotlin.Framework "spring") (ne $.Settings.Kotlin.Framework "micronaut") -}}
     return conn.prepareStatement({{.ConstantName}}).use { stmt ->
       {{.Arg.Bindings}}
 
@@ -653,6 +822,23 @@ class QueriesImpl(private val conn: Connection) : Queries {
       }
       ret
     }
+    {{- else}}
+    return dataSource.connection.use { conn ->
+      conn.prepareStatement({{.ConstantName}}).use { stmt ->
+        {{.Arg.Bindings}}
+
+        val results = stmt.executeQuery()
+        if (!results.next()) {
+          return null
+        }
+        val ret = {{.Ret.ResultSet}}
+        if (results.next()) {
+            throw SQLException("expected one row in result set, but got many")
+        }
+        ret
+      }
+    }
+    {{- end}}
   }
 {{end}}
 
@@ -660,7 +846,10 @@ class QueriesImpl(private val conn: Connection) : Queries {
 {{range .Comments}}//{{.}}
 {{end}}
   @Throws(SQLException::class)
+  {{if or (eq $.Settings.Kotlin.Framework "spring") (eq $.Settings.Kotlin.Framework "micronaut")}}@Transactional(readOnly = {{.ReadOnly}})
+  {{end -}}
   override fun {{.MethodName}}({{.Arg.Args}}): List<{{.Ret.Type}}> {
+    {{if and (ne $.Settings.Kotlin.Framework "spring") (ne $.Settings.Kotlin.Framework "micronaut") -}}
     return conn.prepareStatement({{.ConstantName}}).use { stmt ->
       {{.Arg.Bindings}}
 
@@ -671,6 +860,20 @@ class QueriesImpl(private val conn: Connection) : Queries {
       }
       ret
     }
+    {{- else}}
+    return dataSource.connection.use { conn ->
+      conn.prepareStatement({{.ConstantName}}).use { stmt ->
+        {{.Arg.Bindings}}
+
+        val results = stmt.executeQuery()
+        val ret = mutableListOf<{{.Ret.Type}}>()
+        while (results.next()) {
+            ret.add({{.Ret.ResultSet}})
+        }
+        ret
+      }
+    }
+    {{- end}}
   }
 {{end}}
 
@@ -678,13 +881,25 @@ class QueriesImpl(private val conn: Connection) : Queries {
 {{range .Comments}}//{{.}}
 {{end}}
   @Throws(SQLException::class)
+  {{if or (eq $.Settings.Kotlin.Framework "spring") (eq $.Settings.Kotlin.Framework "micronaut")}}@Transactional(readOnly = {{.ReadOnly}})
+  {{end -}}
   {{ if $.EmitInterface }}override {{ end -}}
   override fun {{.MethodName}}({{.Arg.Args}}) {
+    {{if and (ne $.Settings.Kotlin.Framework "spring") (ne $.Settings.Kotlin.Framework "micronaut") -}}
     conn.prepareStatement({{.ConstantName}}).use { stmt ->
       {{ .Arg.Bindings }}
 
       stmt.execute()
     }
+    {{- else}}
+    dataSource.connection.use { conn ->
+      conn.prepareStatement({{.ConstantName}}).use { stmt ->
+        {{ .Arg.Bindings }}
+
+        stmt.execute()
+      }
+    }
+    {{- end}}
   }
 {{end}}
 
@@ -692,14 +907,27 @@ class QueriesImpl(private val conn: Connection) : Queries {
 {{range .Comments}}//{{.}}
 {{end}}
   @Throws(SQLException::class)
+  {{if or (eq $.Settings.Kotlin.Framework "spring") (eq $.Settings.Kotlin.Framework "micronaut")}}@Transactional(readOnly = {{.ReadOnly}})
+  {{end -}}
   {{ if $.EmitInterface }}override {{ end -}}
   override fun {{.MethodName}}({{.Arg.Args}}): Int {
+    {{if and (ne $.Settings.Kotlin.Framework "spring") (ne $.Settings.Kotlin.Framework "micronaut") -}}
     return conn.prepareStatement({{.ConstantName}}).use { stmt ->
       {{ .Arg.Bindings }}
 
       stmt.execute()
       stmt.updateCount
     }
+    {{- else}}
+    return dataSource.connection.use { conn ->
+      conn.prepareStatement({{.ConstantName}}).use { stmt ->
+        {{ .Arg.Bindings }}
+
+        stmt.execute()
+        stmt.updateCount
+      }
+    }
+    {{- end}}
   }
 {{end}}
 
@@ -707,8 +935,11 @@ class QueriesImpl(private val conn: Connection) : Queries {
 {{range .Comments}}//{{.}}
 {{end}}
   @Throws(SQLException::class)
+  {{if or (eq $.Settings.Kotlin.Framework "spring") (eq $.Settings.Kotlin.Framework "micronaut")}}@Transactional(readOnly = {{.ReadOnly}})
+  {{end -}}
   {{ if $.EmitInterface }}override {{ end -}}
   override fun {{.MethodName}}({{.Arg.Args}}): Long {
+    {{if and (ne $.Settings.Kotlin.Framework "spring") (ne $.Settings.Kotlin.Framework "micronaut") -}}
     return conn.prepareStatement({{.ConstantName}}, Statement.RETURN_GENERATED_KEYS).use { stmt ->
       {{ .Arg.Bindings }}
 
@@ -720,6 +951,234 @@ class QueriesImpl(private val conn: Connection) : Queries {
       }
 	  results.getLong(1)
     }
+    {{- else}}
+    return dataSource.connection.use { conn ->
+      conn.prepareStatement({{.ConstantName}}, Statement.RETURN_GENERATED_KEYS).use { stmt ->
+        {{ .Arg.Bindings }}
+
+        stmt.execute()
+
+        val results = stmt.generatedKeys
+        if (!results.next()) {
+            throw SQLException("no generated key returned")
+        }
+        results.getLong(1)
+      }
+    }
+    {{- end}}
+  }
+{{end}}
+
+{{if eq .Cmd ":copyfrom"}}
+{{range .Comments}}//{{.}}
+{{end}}
+  @Throws(SQLException::class)
+  override fun {{.MethodName}}(rows: Iterable<{{.Arg.Struct.Name}}>) {
+    {{if and (ne $.Settings.Kotlin.Framework "spring") (ne $.Settings.Kotlin.Framework "micronaut") -}}
+    {{if eq $.Settings.Engine "postgresql" -}}
+    val data = rows.joinToString("\n") { row ->
+      listOf(
+        {{- range $i, $e := .Arg.Struct.Fields}}
+        {{- if $i }},{{end}}
+        pgCopyEscape(row.{{.Name}})
+        {{- end}}
+      ).joinToString("\t")
+    }
+    conn.unwrap(PGConnection::class.java).copyAPI.copyIn(
+      "COPY {{.Table}} ({{range $i, $e := .Arg.Struct.Fields}}{{if $i}}, {{end}}{{.Name}}{{end}}) FROM STDIN",
+      StringReader(data + "\n")
+    )
+    {{- else}}
+    conn.prepareStatement({{.ConstantName}}).use { stmt ->
+      for (row in rows) {
+        {{.Arg.BindingsForVar "row"}}
+        stmt.addBatch()
+      }
+      stmt.executeBatch()
+    }
+    {{- end}}
+    {{- else}}
+    dataSource.connection.use { conn ->
+      {{if eq $.Settings.Engine "postgresql" -}}
+      val data = rows.joinToString("\n") { row ->
+        listOf(
+          {{- range $i, $e := .Arg.Struct.Fields}}
+          {{- if $i }},{{end}}
+          pgCopyEscape(row.{{.Name}})
+          {{- end}}
+        ).joinToString("\t")
+      }
+      conn.unwrap(PGConnection::class.java).copyAPI.copyIn(
+        "COPY {{.Table}} ({{range $i, $e := .Arg.Struct.Fields}}{{if $i}}, {{end}}{{.Name}}{{end}}) FROM STDIN",
+        StringReader(data + "\n")
+      )
+      {{- else}}
+      conn.prepareStatement({{.ConstantName}}).use { stmt ->
+        for (row in rows) {
+          {{.Arg.BindingsForVar "row"}}
+          stmt.addBatch()
+        }
+        stmt.executeBatch()
+      }
+      {{- end}}
+    }
+    {{- end}}
+  }
+{{end}}
+
+{{if eq .Cmd ":batchexec"}}
+{{range .Comments}}//{{.}}
+{{end}}
+  @Throws(SQLException::class)
+  override fun {{.MethodName}}(rows: Iterable<{{.Arg.Struct.Name}}>): IntArray {
+    {{if and (ne $.Settings.Kotlin.Framework "spring") (ne $.Settings.Kotlin.Framework "micronaut") -}}
+    return conn.prepareStatement({{.ConstantName}}).use { stmt ->
+      for (row in rows) {
+        {{.Arg.BindingsForVar "row"}}
+        stmt.addBatch()
+      }
+      stmt.executeBatch()
+    }
+    {{- else}}
+    return dataSource.connection.use { conn ->
+      conn.prepareStatement({{.ConstantName}}).use { stmt ->
+        for (row in rows) {
+          {{.Arg.BindingsForVar "row"}}
+          stmt.addBatch()
+        }
+        stmt.executeBatch()
+      }
+    }
+    {{- end}}
+  }
+{{end}}
+{{end}}
+}
+`
+
+var ktR2dbcIfaceTmpl = `// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc {{.SqlcVersion}}
+
+package {{.Package}}
+
+{{range imports .SourceName}}
+{{range .}}import {{.}}
+{{end}}
+{{end}}
+
+interface Queries {
+  {{- range .Queries}}
+  {{- if eq .Cmd ":one"}}
+  suspend fun {{.MethodName}}({{.Arg.Args}}): {{.Ret.Type}}?
+  {{- end}}
+  {{- if eq .Cmd ":many"}}
+  fun {{.MethodName}}({{.Arg.Args}}): Flow<{{.Ret.Type}}>
+  {{- end}}
+  {{- if eq .Cmd ":exec"}}
+  suspend fun {{.MethodName}}({{.Arg.Args}})
+  {{- end}}
+  {{- if eq .Cmd ":execrows"}}
+  suspend fun {{.MethodName}}({{.Arg.Args}}): Int
+  {{- end}}
+  {{- if eq .Cmd ":execresult"}}
+  suspend fun {{.MethodName}}({{.Arg.Args}}): Long
+  {{- end}}
+  {{end}}
+}
+`
+
+// ktR2dbcSqlTmpl mirrors ktSqlTmpl but targets io.r2dbc.spi instead of
+// java.sql, so every method is non-blocking: single-row and exec queries
+// suspend, and :many queries stream their Row publisher as a Flow instead of
+// materializing a List.
+var ktR2dbcSqlTmpl = `// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc {{.SqlcVersion}}
+
+package {{.Package}}
+
+{{range imports .SourceName}}
+{{range .}}import {{.}}
+{{end}}
+{{end}}
+
+{{range .Queries}}
+const val {{.ConstantName}} = {{$.Q}}-- name: {{.MethodName}} {{.Cmd}}
+{{.SQL}}
+{{$.Q}}
+
+{{if .Ret.EmitStruct}}
+data class {{.Ret.Type}} ( {{- range $i, $e := .Ret.Struct.Fields}}
+  {{- if $i }},{{end}}
+  val {{.Name}}: {{.Type}}
+  {{- end}}
+)
+{{end}}
+{{end}}
+
+class QueriesImpl(private val conn: Connection) : Queries {
+{{range .Queries}}
+{{if eq .Cmd ":one"}}
+{{range .Comments}}//{{.}}
+{{end}}
+  override suspend fun {{.MethodName}}({{.Arg.Args}}): {{.Ret.Type}}? {
+    val stmt = conn.createStatement({{.ConstantName}})
+    {{.Arg.R2dbcBindings}}
+
+    return Mono.from(stmt.execute())
+      .flatMap { result -> Mono.from(result.map { row, _ -> {{.Ret.R2dbcResultSet}} }) }
+      .awaitFirstOrNull()
+  }
+{{end}}
+
+{{if eq .Cmd ":many"}}
+{{range .Comments}}//{{.}}
+{{end}}
+  override fun {{.MethodName}}({{.Arg.Args}}): Flow<{{.Ret.Type}}> {
+    val stmt = conn.createStatement({{.ConstantName}})
+    {{.Arg.R2dbcBindings}}
+
+    return Flux.from(stmt.execute())
+      .flatMap { result -> result.map { row, _ -> {{.Ret.R2dbcResultSet}} } }
+      .asFlow()
+  }
+{{end}}
+
+{{if eq .Cmd ":exec"}}
+{{range .Comments}}//{{.}}
+{{end}}
+  override suspend fun {{.MethodName}}({{.Arg.Args}}) {
+    val stmt = conn.createStatement({{.ConstantName}})
+    {{.Arg.R2dbcBindings}}
+
+    Mono.from(stmt.execute()).awaitFirstOrNull()
+  }
+{{end}}
+
+{{if eq .Cmd ":execrows"}}
+{{range .Comments}}//{{.}}
+{{end}}
+  override suspend fun {{.MethodName}}({{.Arg.Args}}): Int {
+    val stmt = conn.createStatement({{.ConstantName}})
+    {{.Arg.R2dbcBindings}}
+
+    return Mono.from(stmt.execute())
+      .flatMap { result -> Mono.from(result.rowsUpdated) }
+      .awaitFirstOrNull() ?: 0
+  }
+{{end}}
+
+{{if eq .Cmd ":execresult"}}
+{{range .Comments}}//{{.}}
+{{end}}
+  override suspend fun {{.MethodName}}({{.Arg.Args}}): Long {
+    val stmt = conn.createStatement({{.ConstantName}}).returnGeneratedValues()
+    {{.Arg.R2dbcBindings}}
+
+    return Mono.from(stmt.execute())
+      .flatMap { result -> Mono.from(result.map { row, _ -> row.get(0, Long::class.java) }) }
+      .awaitFirstOrNull() ?: throw NoSuchElementException("no generated key returned")
   }
 {{end}}
 {{end}}
@@ -732,6 +1191,7 @@ type ktTmplCtx struct {
 	Enums       []Enum
 	DataClasses []Struct
 	Queries     []Query
+	Tables      []Table
 	Settings    *plugin.Settings
 	SqlcVersion string
 
@@ -741,12 +1201,40 @@ type ktTmplCtx struct {
 	EmitJSONTags        bool
 	EmitPreparedQueries bool
 	EmitInterface       bool
+	EmitPgCopyHelper    bool
 }
 
 func Offset(v int) int {
 	return v + 1
 }
 
+// checkFrameworkDriverCompat rejects a Kotlin.Framework setting that isn't
+// compatible with driver, since the DI wiring QueriesConfiguration.kt emits
+// only knows how to construct a jdbc QueriesImpl(dataSource: DataSource);
+// the r2dbc QueriesImpl takes a Connection instead, which that wiring can't
+// supply.
+func checkFrameworkDriverCompat(framework, driver string) error {
+	if driver == "r2dbc" {
+		return fmt.Errorf("kotlin.framework %q is not supported with the r2dbc driver: QueriesImpl(conn: Connection) has no DataSource to wire up", framework)
+	}
+	return nil
+}
+
+// hasPgCopyFrom reports whether any query uses CopyManager-backed
+// :copyfrom on postgresql, which is the only case that needs the
+// pgCopyEscape helper emitted into QueriesImpl.kt.
+func hasPgCopyFrom(queries []Query, engine string) bool {
+	if engine != "postgresql" {
+		return false
+	}
+	for _, q := range queries {
+		if q.Cmd == metadata.CmdCopyFrom {
+			return true
+		}
+	}
+	return false
+}
+
 func ktFormat(s string) string {
 	// TODO: do more than just skip multiple blank lines, like maybe run ktlint to format
 	skipNextSpace := false
@@ -785,18 +1273,25 @@ func Generate(ctx context.Context, req *plugin.CodeGenRequest) (*plugin.CodeGenR
 		"offset":     Offset,
 	}
 
+	sqlTmpl, ifaceTmpl := ktSqlTmpl, ktIfaceTmpl
+	if req.Settings.Kotlin.Driver == "r2dbc" {
+		sqlTmpl, ifaceTmpl = ktR2dbcSqlTmpl, ktR2dbcIfaceTmpl
+	}
+
 	modelsFile := template.Must(template.New("table").Funcs(funcMap).Parse(ktModelsTmpl))
-	sqlFile := template.Must(template.New("table").Funcs(funcMap).Parse(ktSqlTmpl))
-	ifaceFile := template.Must(template.New("table").Funcs(funcMap).Parse(ktIfaceTmpl))
+	sqlFile := template.Must(template.New("table").Funcs(funcMap).Parse(sqlTmpl))
+	ifaceFile := template.Must(template.New("table").Funcs(funcMap).Parse(ifaceTmpl))
 
 	tctx := ktTmplCtx{
-		Settings:    req.Settings,
-		Q:           `"""`,
-		Package:     req.Settings.Kotlin.Package,
-		Queries:     queries,
-		Enums:       enums,
-		DataClasses: structs,
-		SqlcVersion: req.SqlcVersion,
+		Settings:         req.Settings,
+		Q:                `"""`,
+		Package:          req.Settings.Kotlin.Package,
+		Queries:          queries,
+		Enums:            enums,
+		DataClasses:      structs,
+		Tables:           buildTables(structs),
+		SqlcVersion:      req.SqlcVersion,
+		EmitPgCopyHelper: hasPgCopyFrom(queries, req.Settings.Engine),
 	}
 
 	output := map[string]string{}
@@ -827,6 +1322,25 @@ func Generate(ctx context.Context, req *plugin.CodeGenRequest) (*plugin.CodeGenR
 		return nil, err
 	}
 
+	if req.Settings.Kotlin.EmitQueryBuilder {
+		if req.Settings.Kotlin.Driver == "r2dbc" {
+			return nil, fmt.Errorf("kotlin.emit_query_builder is not supported with the r2dbc driver: QueryBuilder.list binds against a blocking java.sql.Connection")
+		}
+		dslFile := template.Must(template.New("table").Funcs(funcMap).Parse(ktQueryBuilderTmpl))
+		if err := execute("QueriesDsl.kt", dslFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Settings.Kotlin.Framework != "" && req.Settings.Kotlin.Framework != "none" {
+		if err := checkFrameworkDriverCompat(req.Settings.Kotlin.Framework, req.Settings.Kotlin.Driver); err != nil {
+			return nil, err
+		}
+		if err := execute("QueriesConfiguration.kt", newConfigurationTemplate(funcMap)); err != nil {
+			return nil, err
+		}
+	}
+
 	resp := plugin.CodeGenResponse{}
 
 	for filename, code := range output {
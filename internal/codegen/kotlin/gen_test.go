@@ -0,0 +1,112 @@
+package kotlin
+
+import (
+	"testing"
+
+	"github.com/kyleconroy/sqlc/internal/metadata"
+)
+
+func TestR2dbcBind(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  ktType
+		want string
+	}{
+		{
+			name: "scalar",
+			typ:  ktType{Name: "String"},
+			want: `stmt.bind(0, status)`,
+		},
+		{
+			name: "nullable scalar",
+			typ:  ktType{Name: "String", IsNull: true},
+			want: `if (status == null) stmt.bindNull(0, String::class.java) else stmt.bind(0, status)`,
+		},
+		{
+			name: "nullable enum",
+			typ:  ktType{Name: "UserStatus", IsEnum: true, IsNull: true},
+			want: `if (status == null) stmt.bindNull(0, String::class.java) else stmt.bind(0, status.value)`,
+		},
+		{
+			name: "nullable array",
+			typ:  ktType{Name: "String", IsArray: true, IsNull: true},
+			want: `if (status == null) stmt.bindNull(0, Array<String>::class.java) else stmt.bind(0, status.toTypedArray())`,
+		},
+		{
+			name: "nullable enum array",
+			typ:  ktType{Name: "UserStatus", IsEnum: true, IsArray: true, IsNull: true},
+			want: `if (status == null) stmt.bindNull(0, Array<String>::class.java) else stmt.bind(0, status.map { v -> v.value }.toTypedArray())`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r2dbcBind(tt.typ, 1, "status")
+			if got != tt.want {
+				t.Errorf("r2dbcBind() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasPgCopyFrom(t *testing.T) {
+	tests := []struct {
+		name    string
+		queries []Query
+		engine  string
+		want    bool
+	}{
+		{
+			name:    "no queries",
+			queries: nil,
+			engine:  "postgresql",
+			want:    false,
+		},
+		{
+			name:    "copyfrom on postgresql",
+			queries: []Query{{Cmd: metadata.CmdCopyFrom}},
+			engine:  "postgresql",
+			want:    true,
+		},
+		{
+			name:    "copyfrom on mysql",
+			queries: []Query{{Cmd: metadata.CmdCopyFrom}},
+			engine:  "mysql",
+			want:    false,
+		},
+		{
+			name:    "non-copyfrom on postgresql",
+			queries: []Query{{Cmd: ":many"}, {Cmd: ":batchexec"}},
+			engine:  "postgresql",
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasPgCopyFrom(tt.queries, tt.engine); got != tt.want {
+				t.Errorf("hasPgCopyFrom() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckFrameworkDriverCompat(t *testing.T) {
+	tests := []struct {
+		name      string
+		framework string
+		driver    string
+		wantErr   bool
+	}{
+		{"spring with jdbc", "spring", "jdbc", false},
+		{"micronaut with jdbc", "micronaut", "jdbc", false},
+		{"spring with r2dbc", "spring", "r2dbc", true},
+		{"micronaut with r2dbc", "micronaut", "r2dbc", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkFrameworkDriverCompat(tt.framework, tt.driver)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkFrameworkDriverCompat(%q, %q) error = %v, wantErr %v", tt.framework, tt.driver, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package kotlin
+
+import (
+	"sort"
+
+	"github.com/kyleconroy/sqlc/internal/metadata"
+	"github.com/kyleconroy/sqlc/internal/plugin"
+)
+
+type importer struct {
+	Settings    *plugin.Settings
+	Enums       []Enum
+	DataClasses []Struct
+	Queries     []Query
+}
+
+func (i *importer) Imports(name string) [][]string {
+	switch name {
+	case "Queries.kt":
+		return i.interfaceImports()
+	case "Models.kt":
+		return i.modelImports()
+	case "QueriesImpl.kt":
+		return i.sqlImports()
+	default:
+		return nil
+	}
+}
+
+func (i *importer) driver() string {
+	if i.Settings != nil && i.Settings.Kotlin.Driver == "r2dbc" {
+		return "r2dbc"
+	}
+	return "jdbc"
+}
+
+func (i *importer) framework() string {
+	if i.Settings == nil {
+		return "none"
+	}
+	return i.Settings.Kotlin.Framework
+}
+
+func (i *importer) interfaceImports() [][]string {
+	if i.driver() == "r2dbc" {
+		return [][]string{
+			{"kotlinx.coroutines.flow.Flow"},
+		}
+	}
+	return [][]string{{"java.sql.SQLException"}}
+}
+
+func (i *importer) modelImports() [][]string {
+	std := map[string]struct{}{}
+	for _, data := range i.DataClasses {
+		for _, f := range data.Fields {
+			addKtTypeImport(std, f.Type)
+		}
+	}
+	for _, q := range i.Queries {
+		if q.Ret.Struct != nil && q.Ret.EmitStruct() {
+			for _, f := range q.Ret.Struct.Fields {
+				addKtTypeImport(std, f.Type)
+			}
+		}
+	}
+	if len(std) == 0 {
+		return nil
+	}
+	return [][]string{sortedKeys(std)}
+}
+
+func (i *importer) sqlImports() [][]string {
+	if i.driver() == "r2dbc" {
+		return i.r2dbcImports()
+	}
+	return i.jdbcImports()
+}
+
+func (i *importer) jdbcImports() [][]string {
+	std := map[string]struct{}{
+		"java.sql.SQLException": {},
+	}
+	switch i.framework() {
+	case "spring":
+		std["javax.sql.DataSource"] = struct{}{}
+		std["org.springframework.stereotype.Repository"] = struct{}{}
+		std["org.springframework.transaction.annotation.Transactional"] = struct{}{}
+	case "micronaut":
+		std["javax.inject.Singleton"] = struct{}{}
+		std["javax.sql.DataSource"] = struct{}{}
+		std["io.micronaut.transaction.annotation.Transactional"] = struct{}{}
+	default:
+		std["java.sql.Connection"] = struct{}{}
+	}
+	for _, q := range i.Queries {
+		if q.Cmd == metadata.CmdExecResult {
+			std["java.sql.Statement"] = struct{}{}
+		}
+		if q.Cmd == metadata.CmdCopyFrom && i.Settings != nil && i.Settings.Engine == "postgresql" {
+			std["org.postgresql.PGConnection"] = struct{}{}
+			std["java.io.StringReader"] = struct{}{}
+		}
+		for _, f := range q.Arg.Struct.JDBCParamBindings {
+			addKtTypeImport(std, f.Type)
+			if f.Type.IsEnum {
+				std["java.sql.Types"] = struct{}{}
+			}
+		}
+		if q.Ret.Struct != nil {
+			for _, f := range q.Ret.Struct.Fields {
+				addKtTypeImport(std, f.Type)
+			}
+		} else {
+			addKtTypeImport(std, q.Ret.Typ)
+		}
+	}
+	return [][]string{sortedKeys(std)}
+}
+
+func (i *importer) r2dbcImports() [][]string {
+	std := map[string]struct{}{
+		"io.r2dbc.spi.Connection":     {},
+		"reactor.core.publisher.Mono": {},
+		"reactor.core.publisher.Flux": {},
+	}
+	coroutines := map[string]struct{}{
+		"kotlinx.coroutines.reactive.awaitFirstOrNull": {},
+		"kotlinx.coroutines.reactive.asFlow":           {},
+		"kotlinx.coroutines.flow.Flow":                 {},
+	}
+	for _, q := range i.Queries {
+		for _, f := range q.Arg.Struct.JDBCParamBindings {
+			addKtTypeImport(std, f.Type)
+		}
+		if q.Ret.Struct != nil {
+			for _, f := range q.Ret.Struct.Fields {
+				addKtTypeImport(std, f.Type)
+			}
+		} else {
+			addKtTypeImport(std, q.Ret.Typ)
+		}
+	}
+	return [][]string{sortedKeys(std), sortedKeys(coroutines)}
+}
+
+func addKtTypeImport(std map[string]struct{}, t ktType) {
+	switch {
+	case t.IsTime():
+		std["java.time."+t.Name] = struct{}{}
+	case t.IsInstant():
+		std["java.time.Instant"] = struct{}{}
+	case t.IsUUID():
+		std["java.util.UUID"] = struct{}{}
+	}
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,225 @@
+package kotlin
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ColumnRef describes one typed column reference generated for the
+// QueryBuilder DSL, e.g. `Users.id`.
+type ColumnRef struct {
+	Name   string // Kotlin property name, e.g. "id"
+	DBName string // underlying column name, e.g. "id"
+	Type   ktType
+}
+
+func (c ColumnRef) KtType() string {
+	return c.Type.String()
+}
+
+// BindArg renders the trailing `bind` lambda passed to a Column<T>
+// constructor, reusing the same enum/array/Instant special-casing as
+// jdbcSet so a value read off a Column binds the same way a hand-written
+// query would. It's empty for a plain scalar column, which relies on
+// Column's default identity binder.
+func (c ColumnRef) BindArg() string {
+	t := c.Type
+	if t.IsEnum && t.IsArray {
+		return fmt.Sprintf(` { conn, v -> conn.createArrayOf("%s", v.map { x -> x.value }.toTypedArray()) }`, t.DataType)
+	}
+	if t.IsEnum {
+		return ` { _, v -> v.value }`
+	}
+	if t.IsArray {
+		return fmt.Sprintf(` { conn, v -> conn.createArrayOf("%s", v.toTypedArray()) }`, t.DataType)
+	}
+	if t.IsInstant() {
+		return ` { _, v -> Timestamp.from(v) }`
+	}
+	return ""
+}
+
+// Table is the DSL's view of a Struct: a singleton object of typed column
+// references plus the query builder that targets it.
+type Table struct {
+	ObjectName string // e.g. "Users"
+	Builder    string // e.g. "UsersQueryBuilder"
+	Struct     *Struct
+	Columns    []ColumnRef
+}
+
+func buildTables(structs []Struct) []Table {
+	var tables []Table
+	for i := range structs {
+		s := &structs[i]
+		objectName := dataClassNameFromTable(s.Table.Name, s.Name)
+		t := Table{
+			ObjectName: objectName,
+			Builder:    objectName + "QueryBuilder",
+			Struct:     s,
+		}
+		for _, f := range s.Fields {
+			t.Columns = append(t.Columns, ColumnRef{
+				Name:   f.Name,
+				DBName: f.DBName,
+				Type:   f.Type,
+			})
+		}
+		tables = append(tables, t)
+	}
+	return tables
+}
+
+// dataClassNameFromTable favors the raw table identifier (usually plural,
+// e.g. "users") over the singularized data class name, so the generated
+// accessor reads as `Users.id` rather than `User.id`.
+func dataClassNameFromTable(tableName, fallback string) string {
+	if tableName == "" {
+		return fallback
+	}
+	out := ""
+	for _, p := range strings.Split(tableName, "_") {
+		out += strings.Title(p)
+	}
+	return out
+}
+
+// ResultSet renders the ResultSet-to-data-class mapping for a table's
+// `SELECT *` row shape, reusing the same jdbcGet column mapping the raw
+// query templates use so the builder and the hand-written queries stay in
+// sync on type handling.
+func (t Table) ResultSet() string {
+	var out []string
+	for i, f := range t.Struct.Fields {
+		out = append(out, jdbcGet(f.Type, i+1))
+	}
+	ret := indent(strings.Join(out, ",\n"), 6, -1)
+	ret = indent(t.Struct.Name+"(\n"+ret+"\n)", 6, 0)
+	return ret
+}
+
+// ktQueryBuilderTmpl generates a fluent, type-safe query builder per table,
+// gated behind Kotlin.EmitQueryBuilder. It's a thin layer on top of the raw
+// prepared-statement queries: every table gets typed Column<T> references
+// and a QueryBuilder that composes .where/.orderBy/.limit into a single
+// SELECT, reusing the same JDBC type mapping as the rest of the package.
+var ktQueryBuilderTmpl = `// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc {{.SqlcVersion}}
+
+package {{.Package}}
+
+import java.sql.Connection
+import java.sql.Timestamp
+
+// Column's bind lambda converts a bound Kotlin value into the object the
+// JDBC driver expects, given the live Connection (needed for conn.createArrayOf).
+// It defaults to the identity conversion, which every other scalar column
+// uses; enum, array and Instant columns pass a column-specific conversion that
+// mirrors jdbcSet's enum/array/Instant handling for the hand-written queries.
+class Column<T>(val name: String, val bind: (Connection, T) -> Any? = { _, v -> v })
+
+class Condition private constructor(private val clauseFn: () -> String, private val binders: List<(Connection) -> Any?>) {
+  fun clause(): String = clauseFn()
+  fun values(conn: Connection): List<Any?> = binders.map { it(conn) }
+
+  companion object {
+    fun <T> binary(column: Column<T>, op: String, value: T): Condition =
+      Condition({ "${column.name} $op ?" }, listOf({ conn: Connection -> column.bind(conn, value) }))
+
+    fun <T> inList(column: Column<T>, values: List<T>): Condition =
+      Condition({ "${column.name} IN (" + values.joinToString(", ") { "?" } + ")" }, values.map { v -> { conn: Connection -> column.bind(conn, v) } })
+  }
+}
+
+infix fun <T> Column<T>.eq(value: T): Condition = Condition.binary(this, "=", value)
+infix fun <T> Column<T>.neq(value: T): Condition = Condition.binary(this, "!=", value)
+infix fun <T> Column<T>.gt(value: T): Condition = Condition.binary(this, ">", value)
+infix fun <T> Column<T>.lt(value: T): Condition = Condition.binary(this, "<", value)
+infix fun <T> Column<T>.like(value: T): Condition = Condition.binary(this, "LIKE", value)
+infix fun <T> Column<T>.` + "`in`" + `(values: List<T>): Condition = Condition.inList(this, values)
+
+// toEngineSql rewrites the builder's "?" placeholders into the target
+// engine's native placeholder syntax immediately before execution, since
+// the builder composes conditions dynamically and can't know the final
+// placeholder count ahead of time the way a static query can.
+private fun toEngineSql(sql: String, engine: String): String {
+  if (engine != "postgresql") {
+    return sql
+  }
+  var n = 0
+  val out = StringBuilder()
+  for (c in sql) {
+    if (c == '?') {
+      n += 1
+      out.append("$").append(n)
+    } else {
+      out.append(c)
+    }
+  }
+  return out.toString()
+}
+
+class QueryBuilder<T>(private val tableName: String, private val engine: String, private val mapper: (java.sql.ResultSet) -> T) {
+  private val conditions = mutableListOf<Condition>()
+  private var orderByColumn: Column<*>? = null
+  private var orderByDesc = false
+  private var limitValue: Int? = null
+
+  fun where(condition: Condition): QueryBuilder<T> {
+    conditions.add(condition)
+    return this
+  }
+
+  fun orderBy(column: Column<*>, desc: Boolean = false): QueryBuilder<T> {
+    orderByColumn = column
+    orderByDesc = desc
+    return this
+  }
+
+  fun limit(n: Int): QueryBuilder<T> {
+    limitValue = n
+    return this
+  }
+
+  private fun buildSql(): String {
+    val sb = StringBuilder("SELECT * FROM ").append(tableName)
+    if (conditions.isNotEmpty()) {
+      sb.append(" WHERE ")
+      sb.append(conditions.joinToString(" AND ") { c -> c.clause() })
+    }
+    orderByColumn?.let { sb.append(" ORDER BY ").append(it.name).append(if (orderByDesc) " DESC" else "") }
+    limitValue?.let { sb.append(" LIMIT ").append(it) }
+    return toEngineSql(sb.toString(), engine)
+  }
+
+  fun list(conn: Connection): List<T> {
+    val sql = buildSql()
+    return conn.prepareStatement(sql).use { stmt ->
+      var i = 1
+      conditions.forEach { c -> c.values(conn).forEach { v -> stmt.setObject(i, v); i += 1 } }
+      val results = stmt.executeQuery()
+      val out = mutableListOf<T>()
+      while (results.next()) {
+        out.add(mapper(results))
+      }
+      out
+    }
+  }
+
+  fun first(conn: Connection): T? {
+    return limit(1).list(conn).firstOrNull()
+  }
+}
+{{range .Tables}}
+object {{.ObjectName}} {
+  {{- range .Columns}}
+  val {{.Name}} = Column<{{.KtType}}>("{{.DBName}}"){{.BindArg}}
+  {{- end}}
+
+  fun query(): QueryBuilder<{{.Struct.Name}}> = QueryBuilder("{{.Struct.Table.Name}}", "{{$.Settings.Engine}}") { row ->
+{{.ResultSet}}
+  }
+}
+{{end}}
+`
@@ -0,0 +1,45 @@
+package kotlin
+
+import "testing"
+
+func TestColumnRefBindArg(t *testing.T) {
+	tests := []struct {
+		name string
+		typ  ktType
+		want string
+	}{
+		{
+			name: "scalar",
+			typ:  ktType{Name: "String"},
+			want: "",
+		},
+		{
+			name: "enum",
+			typ:  ktType{Name: "UserStatus", IsEnum: true},
+			want: ` { _, v -> v.value }`,
+		},
+		{
+			name: "array",
+			typ:  ktType{Name: "String", IsArray: true, DataType: "text"},
+			want: ` { conn, v -> conn.createArrayOf("text", v.toTypedArray()) }`,
+		},
+		{
+			name: "enum array",
+			typ:  ktType{Name: "UserStatus", IsEnum: true, IsArray: true, DataType: "user_status"},
+			want: ` { conn, v -> conn.createArrayOf("user_status", v.map { x -> x.value }.toTypedArray()) }`,
+		},
+		{
+			name: "instant",
+			typ:  ktType{Name: "Instant"},
+			want: ` { _, v -> Timestamp.from(v) }`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := ColumnRef{Name: "col", DBName: "col", Type: tt.typ}
+			if got := c.BindArg(); got != tt.want {
+				t.Errorf("BindArg() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
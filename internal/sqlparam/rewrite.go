@@ -0,0 +1,180 @@
+// Package sqlparam rewrites PostgreSQL-style numbered parameters ($1, $2,
+// ...) embedded in a query string into the placeholder syntax a given
+// driver expects. It understands just enough SQL lexical structure to avoid
+// touching a `$N`-shaped sequence that appears inside a string literal or a
+// comment, which a plain regular expression substitution cannot do safely.
+package sqlparam
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Style selects the placeholder syntax a rewrite targets.
+type Style int
+
+const (
+	// QuestionMark rewrites every numbered parameter to "?", the syntax
+	// used by JDBC and MySQL.
+	QuestionMark Style = iota
+	// Named rewrites every numbered parameter to a colon-prefixed name
+	// built from its original number, e.g. "$1" becomes ":p1".
+	Named
+)
+
+// Rewrite walks sql and replaces every numbered parameter reference with
+// the placeholder syntax selected by style. It returns the rewritten SQL
+// along with order, the original parameter number backing each placeholder
+// that appears in the output, in left-to-right order. A repeated reference
+// to the same original parameter (e.g. "$1" used twice) appears twice in
+// order, once per occurrence, so callers can re-materialize per-occurrence
+// bindings the same way the existing JDBCParamBindings dedup logic does.
+func Rewrite(sql string, style Style) (rewritten string, order []int, err error) {
+	var out strings.Builder
+	r := []rune(sql)
+	i := 0
+	for i < len(r) {
+		c := r[i]
+		switch {
+		case c == '\'':
+			j, ok := scanQuoted(r, i, '\'')
+			if !ok {
+				return "", nil, fmt.Errorf("sqlparam: unterminated string literal at offset %d", i)
+			}
+			out.WriteString(string(r[i:j]))
+			i = j
+
+		case c == 'E' && i+1 < len(r) && r[i+1] == '\'':
+			j, ok := scanEscapedQuoted(r, i+1)
+			if !ok {
+				return "", nil, fmt.Errorf("sqlparam: unterminated escape string at offset %d", i)
+			}
+			out.WriteString(string(r[i:j]))
+			i = j
+
+		case c == '-' && i+1 < len(r) && r[i+1] == '-':
+			j := i
+			for j < len(r) && r[j] != '\n' {
+				j++
+			}
+			out.WriteString(string(r[i:j]))
+			i = j
+
+		case c == '/' && i+1 < len(r) && r[i+1] == '*':
+			rest := string(r[i+2:])
+			j := strings.Index(rest, "*/")
+			if j < 0 {
+				return "", nil, fmt.Errorf("sqlparam: unterminated block comment at offset %d", i)
+			}
+			end := i + 2 + utf8.RuneCountInString(rest[:j]) + 2
+			out.WriteString(string(r[i:end]))
+			i = end
+
+		case c == '$' && i+1 < len(r) && unicode.IsDigit(r[i+1]):
+			j := i + 1
+			for j < len(r) && unicode.IsDigit(r[j]) {
+				j++
+			}
+			n, convErr := strconv.Atoi(string(r[i+1 : j]))
+			if convErr != nil {
+				return "", nil, fmt.Errorf("sqlparam: invalid parameter number at offset %d: %w", i, convErr)
+			}
+			order = append(order, n)
+			out.WriteString(placeholder(style, n))
+			i = j
+
+		case c == '$':
+			if end, ok := scanDollarQuoted(r, i); ok {
+				out.WriteString(string(r[i:end]))
+				i = end
+				continue
+			}
+			out.WriteRune(c)
+			i++
+
+		default:
+			out.WriteRune(c)
+			i++
+		}
+	}
+	return out.String(), order, nil
+}
+
+func placeholder(style Style, n int) string {
+	switch style {
+	case Named:
+		return fmt.Sprintf(":p%d", n)
+	default:
+		return "?"
+	}
+}
+
+// scanQuoted returns the index just past the closing quote of a plain
+// '...' string literal that starts at start (which must point at the
+// opening quote), treating a doubled quote (`''`) as an escaped quote
+// rather than the end of the literal. Under PostgreSQL's default
+// standard_conforming_strings setting, backslash has no special meaning in
+// a plain string literal, so it is not treated as an escape character here.
+func scanQuoted(r []rune, start int, quote rune) (int, bool) {
+	i := start + 1
+	for i < len(r) {
+		if r[i] == quote {
+			if i+1 < len(r) && r[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1, true
+		}
+		i++
+	}
+	return 0, false
+}
+
+// scanEscapedQuoted is like scanQuoted but for PostgreSQL's E'...' escape
+// string syntax, where a backslash does escape the character that follows
+// it (including a backslash-escaped quote, \'), in addition to a doubled
+// quote (`''`).
+func scanEscapedQuoted(r []rune, start int) (int, bool) {
+	i := start + 1
+	for i < len(r) {
+		if r[i] == '\\' && i+1 < len(r) {
+			i += 2
+			continue
+		}
+		if r[i] == '\'' {
+			if i+1 < len(r) && r[i+1] == '\'' {
+				i += 2
+				continue
+			}
+			return i + 1, true
+		}
+		i++
+	}
+	return 0, false
+}
+
+// scanDollarQuoted recognizes a dollar-quoted string ($tag$...$tag$,
+// including the anonymous $$...$$ form) starting at start, which must point
+// at the opening '$'. It returns the index just past the closing delimiter.
+func scanDollarQuoted(r []rune, start int) (int, bool) {
+	i := start + 1
+	tagStart := i
+	for i < len(r) && (unicode.IsLetter(r[i]) || unicode.IsDigit(r[i]) || r[i] == '_') {
+		i++
+	}
+	if i >= len(r) || r[i] != '$' {
+		return 0, false
+	}
+	tag := string(r[tagStart:i])
+	delim := "$" + tag + "$"
+	bodyStart := i + 1
+	body := string(r[bodyStart:])
+	idx := strings.Index(body, delim)
+	if idx < 0 {
+		return 0, false
+	}
+	return bodyStart + utf8.RuneCountInString(body[:idx]) + utf8.RuneCountInString(delim), true
+}
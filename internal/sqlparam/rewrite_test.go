@@ -0,0 +1,98 @@
+package sqlparam
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRewriteQuestionMark(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+		ord  []int
+	}{
+		{
+			name: "simple",
+			sql:  "SELECT * FROM foo WHERE id = $1",
+			want: "SELECT * FROM foo WHERE id = ?",
+			ord:  []int{1},
+		},
+		{
+			name: "duplicate reference",
+			sql:  "SELECT * FROM foo WHERE id = $1 OR parent_id = $1",
+			want: "SELECT * FROM foo WHERE id = ? OR parent_id = ?",
+			ord:  []int{1, 1},
+		},
+		{
+			name: "literal containing a dollar sequence",
+			sql:  "SELECT * FROM foo WHERE name = '$1' AND id = $1",
+			want: "SELECT * FROM foo WHERE name = '$1' AND id = ?",
+			ord:  []int{1},
+		},
+		{
+			name: "dollar-quoted body",
+			sql:  "SELECT $tag$contains $1 literally$tag$ WHERE id = $2",
+			want: "SELECT $tag$contains $1 literally$tag$ WHERE id = ?",
+			ord:  []int{2},
+		},
+		{
+			name: "anonymous dollar-quoted body",
+			sql:  "SELECT $$has $1 inside$$ WHERE id = $2",
+			want: "SELECT $$has $1 inside$$ WHERE id = ?",
+			ord:  []int{2},
+		},
+		{
+			name: "escape string literal",
+			sql:  `SELECT * FROM foo WHERE name = E'it\'s $1' AND id = $1`,
+			want: `SELECT * FROM foo WHERE name = E'it\'s $1' AND id = ?`,
+			ord:  []int{1},
+		},
+		{
+			name: "plain literal containing a backslash",
+			sql:  `SELECT * FROM t WHERE name = 'abc\' AND id = $1`,
+			want: `SELECT * FROM t WHERE name = 'abc\' AND id = ?`,
+			ord:  []int{1},
+		},
+		{
+			name: "line comment",
+			sql:  "-- references $1 in a comment\nSELECT * FROM foo WHERE id = $2",
+			want: "-- references $1 in a comment\nSELECT * FROM foo WHERE id = ?",
+			ord:  []int{2},
+		},
+		{
+			name: "block comment",
+			sql:  "/* references $1 */ SELECT * FROM foo WHERE id = $2",
+			want: "/* references $1 */ SELECT * FROM foo WHERE id = ?",
+			ord:  []int{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ord, err := Rewrite(tt.sql, QuestionMark)
+			if err != nil {
+				t.Fatalf("Rewrite() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Rewrite() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(ord, tt.ord) {
+				t.Errorf("order = %v, want %v", ord, tt.ord)
+			}
+		})
+	}
+}
+
+func TestRewriteNamed(t *testing.T) {
+	got, ord, err := Rewrite("SELECT * FROM foo WHERE id = $1 AND parent_id = $2", Named)
+	if err != nil {
+		t.Fatalf("Rewrite() error = %v", err)
+	}
+	want := "SELECT * FROM foo WHERE id = :p1 AND parent_id = :p2"
+	if got != want {
+		t.Errorf("Rewrite() = %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(ord, []int{1, 2}) {
+		t.Errorf("order = %v, want [1 2]", ord)
+	}
+}